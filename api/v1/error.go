@@ -0,0 +1,25 @@
+package log_v1
+
+import (
+	"fmt"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ErrOffsetOutOfRange is returned when a consumer asks for an offset the log
+// hasn't written yet (or has already truncated).
+type ErrOffsetOutOfRange struct {
+	Offset uint64
+}
+
+func (e ErrOffsetOutOfRange) GRPCStatus() *status.Status {
+	return status.New(
+		codes.OutOfRange,
+		fmt.Sprintf("offset out of range: %d", e.Offset),
+	)
+}
+
+func (e ErrOffsetOutOfRange) Error() string {
+	return e.GRPCStatus().Err().Error()
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/siluk00/proglog/internal/agent"
+	"github.com/siluk00/proglog/internal/config"
+	"github.com/siluk00/proglog/internal/telemetry"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// cli bundles the cobra command together with the agent config it builds
+// from flags/env/config file, so Execute has somewhere to write into.
+type cli struct {
+	cmd    *cobra.Command
+	config cfg
+}
+
+// cfg is the flag-friendly, pre-TLS-resolution shape of agent.Config.
+type cfg struct {
+	agent.Config
+	ServerTLSConfig tlsConfig
+	PeerTLSConfig   tlsConfig
+}
+
+type tlsConfig struct {
+	CertFile string
+	KeyFile  string
+	CAFile   string
+}
+
+func newCLI() *cli {
+	c := &cli{}
+	cmd := &cobra.Command{
+		Use:     "proglog",
+		PreRunE: c.setupConfig,
+		RunE:    c.run,
+	}
+	setupFlags(cmd)
+	c.cmd = cmd
+	return c
+}
+
+func setupFlags(cmd *cobra.Command) {
+	cmd.Flags().String("config-file", "", "Path to config file.")
+
+	dataDir := "/var/lib/proglog"
+	cmd.Flags().String("data-dir", dataDir, "Directory to store log and raft data.")
+	cmd.Flags().String("node-name", "", "Unique server ID.")
+	cmd.Flags().String("bind-addr", "127.0.0.1:8401", "Address to bind Serf on.")
+	cmd.Flags().Int("rpc-port", 8400, "Port for RPC clients (and Raft) connections.")
+	cmd.Flags().StringSlice("start-join-addrs", nil, "Serf addresses to join.")
+	cmd.Flags().Bool("bootstrap", false, "Bootstrap a new Raft cluster with this node as its first voter. Set on exactly one node per new cluster; every other node should join via --start-join-addrs instead.")
+	cmd.Flags().String("acl-model-file", "", "Path to ACL model.")
+	cmd.Flags().String("acl-policy-file", "", "Path to ACL policy.")
+
+	cmd.Flags().String("telemetry-exporter", "", "Telemetry exporter to publish traces/metrics through: \"prometheus\" or \"otlp\". Empty disables telemetry.")
+	cmd.Flags().String("telemetry-service-name", "proglog", "Service name attached to every span and metric.")
+	cmd.Flags().String("telemetry-admin-addr", ":8402", "Address the Prometheus /metrics endpoint is served on. Only used when telemetry-exporter is \"prometheus\".")
+	cmd.Flags().String("telemetry-otlp-endpoint", "", "Collector address traces/metrics are shipped to. Only used when telemetry-exporter is \"otlp\".")
+	cmd.Flags().Bool("telemetry-otlp-insecure", false, "Disable transport security on the OTLP-gRPC connection. Only used when telemetry-exporter is \"otlp\".")
+
+	cmd.Flags().String("server-tls-cert-file", "", "Path to server tls cert.")
+	cmd.Flags().String("server-tls-key-file", "", "Path to server tls key.")
+	cmd.Flags().String("server-tls-ca-file", "", "Path to root CA that client certs are verified against.")
+
+	cmd.Flags().String("peer-tls-cert-file", "", "Path to peer tls cert. A non-leader forwards writes to the leader over this cert, and the leader authorizes the forwarded write as this cert's CN, not the original client's - grant this CN produce in the ACL policy on every node, or forwarded writes will fail with PermissionDenied.")
+	cmd.Flags().String("peer-tls-key-file", "", "Path to peer tls key.")
+	cmd.Flags().String("peer-tls-ca-file", "", "Path to root CA that the far end's cert is verified against on peer connections.")
+
+	_ = viper.BindPFlags(cmd.Flags())
+}
+
+func (c *cli) setupConfig(cmd *cobra.Command, args []string) error {
+	configFile, err := cmd.Flags().GetString("config-file")
+	if err != nil {
+		return err
+	}
+	viper.SetConfigFile(configFile)
+
+	if err = viper.ReadInConfig(); err != nil {
+		if _, ok := err.(viper.ConfigFileNotFoundError); !ok {
+			return err
+		}
+	}
+
+	c.config.DataDir = viper.GetString("data-dir")
+	c.config.NodeName = viper.GetString("node-name")
+	c.config.BindAddr = viper.GetString("bind-addr")
+	c.config.RPCPort = viper.GetInt("rpc-port")
+	c.config.StartJoinAddrs = viper.GetStringSlice("start-join-addrs")
+	c.config.Bootstrap = viper.GetBool("bootstrap")
+	c.config.ACLModelFile = viper.GetString("acl-model-file")
+	c.config.ACLPolicyFile = viper.GetString("acl-policy-file")
+	c.config.ServerTLSConfig.CertFile = viper.GetString("server-tls-cert-file")
+	c.config.ServerTLSConfig.KeyFile = viper.GetString("server-tls-key-file")
+	c.config.ServerTLSConfig.CAFile = viper.GetString("server-tls-ca-file")
+	c.config.PeerTLSConfig.CertFile = viper.GetString("peer-tls-cert-file")
+	c.config.PeerTLSConfig.KeyFile = viper.GetString("peer-tls-key-file")
+	c.config.PeerTLSConfig.CAFile = viper.GetString("peer-tls-ca-file")
+
+	if exporter := viper.GetString("telemetry-exporter"); exporter != "" {
+		c.config.Config.TelemetryConfig = &telemetry.Config{
+			ServiceName:  viper.GetString("telemetry-service-name"),
+			Exporter:     telemetry.Exporter(exporter),
+			AdminAddr:    viper.GetString("telemetry-admin-addr"),
+			OTLPEndpoint: viper.GetString("telemetry-otlp-endpoint"),
+			OTLPInsecure: viper.GetBool("telemetry-otlp-insecure"),
+		}
+	}
+
+	if c.config.ServerTLSConfig.CertFile != "" && c.config.ServerTLSConfig.KeyFile != "" {
+		c.config.Config.ServerTLSConfig, err = config.SetupTLSConfig(config.TLSConfig{
+			CertFile: c.config.ServerTLSConfig.CertFile,
+			KeyFile:  c.config.ServerTLSConfig.KeyFile,
+			CAFile:   c.config.ServerTLSConfig.CAFile,
+			Server:   true,
+		})
+		if err != nil {
+			return err
+		}
+		c.config.Config.ServerCAFile = c.config.ServerTLSConfig.CAFile
+	}
+
+	// Peer connections are node-to-node: this node is a client when it dials
+	// a peer to replicate and a server when that peer dials it back, so the
+	// peer cert needs both EKUs and SetupTLSConfig is asked to build both
+	// halves of the handshake.
+	if c.config.PeerTLSConfig.CertFile != "" && c.config.PeerTLSConfig.KeyFile != "" {
+		c.config.Config.PeerTLSConfig, err = config.SetupTLSConfig(config.TLSConfig{
+			CertFile: c.config.PeerTLSConfig.CertFile,
+			KeyFile:  c.config.PeerTLSConfig.KeyFile,
+			CAFile:   c.config.PeerTLSConfig.CAFile,
+			Peer:     true,
+		})
+		if err != nil {
+			return err
+		}
+		c.config.Config.PeerCAFile = c.config.PeerTLSConfig.CAFile
+	}
+
+	return nil
+}
+
+func (c *cli) run(cmd *cobra.Command, args []string) error {
+	var err error
+	agent, err := agent.New(c.config.Config)
+	if err != nil {
+		return err
+	}
+
+	sigc := make(chan os.Signal, 1)
+	signal.Notify(sigc, syscall.SIGINT, syscall.SIGTERM)
+	<-sigc
+
+	return agent.Shutdown()
+}
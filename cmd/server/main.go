@@ -2,11 +2,11 @@ package main
 
 import (
 	"log"
-
-	"github.com/siluk00/proglog/internal/server"
 )
 
 func main() {
-	srv := server.NewHTTPServer(":8080")
-	log.Fatal(srv.ListenAndServe())
+	cli := newCLI()
+	if err := cli.cmd.Execute(); err != nil {
+		log.Fatal(err)
+	}
 }
@@ -0,0 +1,320 @@
+// Package agent wires together everything a running proglog node needs:
+// the Raft-replicated log, the gRPC server, and Serf membership, which
+// drives the log's cluster membership as peers join and leave.
+package agent
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/siluk00/proglog/internal/auth"
+	"github.com/siluk00/proglog/internal/config"
+	"github.com/siluk00/proglog/internal/discovery"
+	"github.com/siluk00/proglog/internal/distributed"
+	"github.com/siluk00/proglog/internal/server"
+	"github.com/siluk00/proglog/internal/telemetry"
+
+	"github.com/hashicorp/raft"
+	"github.com/soheilhy/cmux"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// Config holds everything an Agent needs to start: where to bind, which
+// peers to gossip with, and what certs (if any) to present on the wire.
+type Config struct {
+	ServerTLSConfig *tls.Config
+	PeerTLSConfig   *tls.Config
+
+	// ServerCAFile and PeerCAFile, when set, point at the CA file(s) backing
+	// ServerTLSConfig/PeerTLSConfig on disk. The agent watches them and
+	// verifies each new gRPC connection, served or dialed to a peer, against
+	// the CA pool currently on disk, so a CA rotation doesn't require
+	// restarting the node. Raft's StreamLayer uses ServerTLSConfig and
+	// PeerTLSConfig as given, so its connections keep the pools loaded at
+	// startup.
+	ServerCAFile string
+	PeerCAFile   string
+
+	DataDir        string
+	BindAddr       string
+	RPCPort        int
+	NodeName       string
+	StartJoinAddrs []string
+	ACLModelFile   string
+	ACLPolicyFile  string
+	// Bootstrap marks this node as the first voter of a new Raft cluster.
+	// Exactly one node in a freshly-created cluster should set this; every
+	// other node joins through StartJoinAddrs/Serf instead.
+	Bootstrap bool
+
+	// TelemetryConfig selects and configures the OpenTelemetry exporter the
+	// node's gRPC server publishes traces and metrics through. Nil disables
+	// telemetry, same as a nil *server.Config.TelemetryConfig.
+	TelemetryConfig *telemetry.Config
+}
+
+func (c Config) RPCAddr() (string, error) {
+	host, _, err := net.SplitHostPort(c.BindAddr)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s:%d", host, c.RPCPort), nil
+}
+
+// Agent starts and owns an instance of every subsystem a node runs, and
+// tears them all down, in order, on Shutdown.
+type Agent struct {
+	Config
+
+	mux        cmux.CMux
+	log        *distributed.DistributedLog
+	server     *grpc.Server
+	membership *discovery.Membership
+	telemetry  *telemetry.Telemetry
+
+	caWatchers []*config.CertPoolWatcher
+
+	shutdown     bool
+	shutdowns    chan struct{}
+	shutdownLock sync.Mutex
+}
+
+func New(config Config) (*Agent, error) {
+	a := &Agent{
+		Config:    config,
+		shutdowns: make(chan struct{}),
+	}
+
+	setup := []func() error{
+		a.setupLogger,
+		a.setupMux,
+		a.setupLog,
+		a.setupServer,
+		a.setupMembership,
+	}
+	for _, fn := range setup {
+		if err := fn(); err != nil {
+			return nil, err
+		}
+	}
+
+	go func() {
+		if err := a.mux.Serve(); err != nil {
+			_ = a.Shutdown()
+		}
+	}()
+
+	return a, nil
+}
+
+func (a *Agent) setupLogger() error {
+	logger, err := zap.NewDevelopment()
+	if err != nil {
+		return err
+	}
+	zap.ReplaceGlobals(logger)
+	return nil
+}
+
+// setupMux opens the RPC listener and wraps it in a cmux.CMux, so gRPC and
+// Raft traffic can share one TCP port (a.RPCPort) instead of needing one
+// listener each.
+func (a *Agent) setupMux() error {
+	rpcAddr, err := a.RPCAddr()
+	if err != nil {
+		return err
+	}
+	ln, err := net.Listen("tcp", rpcAddr)
+	if err != nil {
+		return err
+	}
+	a.mux = cmux.New(ln)
+	return nil
+}
+
+// setupLog builds the Raft-replicated log, demuxing Raft's share of the
+// RPC listener off a.mux by the RaftRPC byte prefix documented in
+// internal/distributed.StreamLayer. Registering this matcher before
+// setupServer's cmux.Any() match gives it priority over the gRPC
+// catch-all.
+func (a *Agent) setupLog() error {
+	raftLn := a.mux.Match(cmux.PrefixMatcher(string([]byte{byte(distributed.RaftRPC)})))
+
+	raftConfig := distributed.Config{}
+	raftConfig.Raft.StreamLayer = distributed.NewStreamLayer(
+		raftLn, a.ServerTLSConfig, a.PeerTLSConfig,
+	)
+	raftConfig.Raft.LocalID = raft.ServerID(a.NodeName)
+	raftConfig.Raft.Bootstrap = a.Bootstrap
+
+	var err error
+	a.log, err = distributed.NewDistributedLog(a.DataDir, raftConfig)
+	if err != nil {
+		return err
+	}
+
+	if a.Bootstrap {
+		err = a.log.WaitForLeader(3 * time.Second)
+	}
+	return err
+}
+
+func (a *Agent) setupServer() error {
+	authorizer, err := auth.New(a.ACLModelFile, a.ACLPolicyFile)
+	if err != nil {
+		return err
+	}
+
+	peerOpts, err := a.peerDialOptions()
+	if err != nil {
+		return err
+	}
+
+	serverConfig := &server.Config{
+		CommitLog:         a.log,
+		Authorizer:        authorizer,
+		PeerClientOptions: peerOpts,
+		TelemetryConfig:   a.TelemetryConfig,
+	}
+
+	var opts []grpc.ServerOption
+	if a.ServerTLSConfig != nil {
+		opts = append(opts, grpc.Creds(a.serverCreds()))
+	}
+
+	a.server, err = server.NewGRPCServer(serverConfig, opts...)
+	if err != nil {
+		return err
+	}
+	a.telemetry = serverConfig.Telemetry
+
+	grpcLn := a.mux.Match(cmux.Any())
+	go func() {
+		if err := a.server.Serve(grpcLn); err != nil {
+			_ = a.Shutdown()
+		}
+	}()
+
+	return nil
+}
+
+// serverCreds builds the transport credentials the gRPC server listens
+// with. When ServerCAFile is set, client certs are verified against a
+// CertPoolWatcher instead of a fixed pool, so a rotated CA keeps working.
+func (a *Agent) serverCreds() credentials.TransportCredentials {
+	if a.ServerCAFile == "" {
+		return credentials.NewTLS(a.ServerTLSConfig)
+	}
+
+	watcher, err := config.NewCertPoolWatcher(a.ServerCAFile)
+	if err != nil {
+		zap.L().Named("agent").Error("failed to watch server ca file, falling back to a static pool",
+			zap.Error(err), zap.String("file", a.ServerCAFile))
+		return credentials.NewTLS(a.ServerTLSConfig)
+	}
+	a.caWatchers = append(a.caWatchers, watcher)
+
+	return config.NewDynamicTLSCredentials(a.ServerTLSConfig, watcher)
+}
+
+// peerCreds builds the transport credentials used to dial peers, watching
+// PeerCAFile the same way serverCreds watches ServerCAFile.
+func (a *Agent) peerCreds() (credentials.TransportCredentials, error) {
+	if a.PeerCAFile == "" {
+		return credentials.NewTLS(a.PeerTLSConfig), nil
+	}
+
+	watcher, err := config.NewCertPoolWatcher(a.PeerCAFile)
+	if err != nil {
+		return nil, err
+	}
+	a.caWatchers = append(a.caWatchers, watcher)
+
+	return config.NewDynamicTLSCredentials(a.PeerTLSConfig, watcher), nil
+}
+
+// peerDialOptions builds the grpc.DialOptions the server uses to dial
+// another node in the cluster when it forwards a write to the Raft leader.
+func (a *Agent) peerDialOptions() ([]grpc.DialOption, error) {
+	if a.PeerTLSConfig == nil {
+		return []grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())}, nil
+	}
+	creds, err := a.peerCreds()
+	if err != nil {
+		return nil, err
+	}
+	return []grpc.DialOption{grpc.WithTransportCredentials(creds)}, nil
+}
+
+// setupMembership gossips membership over Serf and drives the Raft
+// cluster's membership directly from it: a.log satisfies
+// discovery.Handler, so as peers join and leave the Serf cluster they're
+// added to and removed from the Raft cluster as voters.
+func (a *Agent) setupMembership() error {
+	rpcAddr, err := a.RPCAddr()
+	if err != nil {
+		return err
+	}
+
+	a.membership, err = discovery.New(a.log, discovery.Config{
+		NodeName: a.NodeName,
+		BindAddr: a.BindAddr,
+		Tags: map[string]string{
+			"rpc_addr": rpcAddr,
+		},
+		StartJoinAddrs: a.StartJoinAddrs,
+	})
+	return err
+}
+
+// Shutdown stops every subsystem, in the reverse order they were started,
+// and is safe to call more than once.
+func (a *Agent) Shutdown() error {
+	a.shutdownLock.Lock()
+	defer a.shutdownLock.Unlock()
+
+	if a.shutdown {
+		return nil
+	}
+	a.shutdown = true
+	close(a.shutdowns)
+
+	shutdown := []func() error{
+		a.membership.Leave,
+		func() error {
+			a.server.GracefulStop()
+			return nil
+		},
+		func() error {
+			return a.telemetry.Shutdown(context.Background())
+		},
+		a.log.Close,
+		func() error {
+			a.mux.Close()
+			return nil
+		},
+		a.closeCAWatchers,
+	}
+	for _, fn := range shutdown {
+		if err := fn(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (a *Agent) closeCAWatchers() error {
+	for _, w := range a.caWatchers {
+		if err := w.Close(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
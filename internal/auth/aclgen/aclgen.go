@@ -0,0 +1,66 @@
+// Package aclgen writes the Casbin model/policy file pair auth.New needs
+// to t.TempDir(), so tests can exercise CasbinAuthorizer without depending
+// on fixtures committed to - or expected to already exist on - disk,
+// mirroring how internal/config/certgen replaces static TLS fixtures.
+package aclgen
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// model is the ACL model every proglog deployment uses: a subject may
+// perform an action on an object only if a matching policy line grants it.
+const model = `
+[request_definition]
+r = sub, obj, act
+
+[policy_definition]
+p = sub, obj, act
+
+[policy_effect]
+e = some(where (p.eft == allow))
+
+[matchers]
+m = r.sub == p.sub && r.obj == p.obj && r.act == p.act
+`
+
+// rootOnlyPolicy grants "root" produce, consume, and cluster membership
+// changes on every object and nobody else anything, matching the policy
+// the test fixtures this replaces used to ship. Each line's leading "p"
+// selects the policy_definition ptype declared in model.conf.
+const rootOnlyPolicy = `p, root, *, produce
+p, root, *, consume
+p, root, *, cluster
+`
+
+// NewTestACL writes model.conf and policy.csv to t.TempDir() and returns
+// their paths, ready to pass to auth.New. The policy grants "root" full
+// access and nobody else anything.
+func NewTestACL(t *testing.T) (modelFile, policyFile string) {
+	t.Helper()
+	return NewTestACLWithPolicy(t, rootOnlyPolicy)
+}
+
+// NewTestACLWithPolicy is NewTestACL for tests that need a policy other
+// than root-only, e.g. granting a non-root CN produce rights to exercise
+// leader forwarding, which authorizes using the forwarding peer's CN
+// rather than the original client's.
+func NewTestACLWithPolicy(t *testing.T, policy string) (modelFile, policyFile string) {
+	t.Helper()
+	dir := t.TempDir()
+
+	modelFile = writeFile(t, dir, "model.conf", model)
+	policyFile = writeFile(t, dir, "policy.csv", policy)
+	return modelFile, policyFile
+}
+
+func writeFile(t *testing.T, dir, name, contents string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	require.NoError(t, os.WriteFile(path, []byte(contents), 0o644))
+	return path
+}
@@ -1,33 +1,25 @@
+// Package auth decides whether a subject may perform an action on an
+// object, pluggable behind the Authorizer interface so the gRPC server
+// doesn't care whether the decision comes from Casbin, OPA, or a
+// combination of backends.
 package auth
 
 import (
 	"fmt"
 
-	"github.com/casbin/casbin/v2"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 )
 
-func New(model, policy string) (*Authorizer, error) {
-	e, err := casbin.NewEnforcer(model, policy)
-	if err != nil {
-		return nil, err
-	}
-	return &Authorizer{enforcer: e}, nil
+// Authorizer decides whether subject is permitted to perform action on
+// object. Implementations return a gRPC PermissionDenied status error (see
+// permissionDenied) when the decision is deny, so callers can propagate it
+// straight to the client.
+type Authorizer interface {
+	Authorize(subject, object, action string) error
 }
 
-type Authorizer struct {
-	enforcer *casbin.Enforcer
-}
-
-func (a *Authorizer) Authorize(subject, object, action string) error {
-	if enforced, err := a.enforcer.Enforce(subject, object, action); err != nil {
-		return err
-	} else if !enforced {
-		msg := fmt.Sprintf("%s not permitted to %s to %s", subject, object, action)
-		st := status.New(codes.PermissionDenied, msg)
-		return st.Err()
-	}
-
-	return nil
+func permissionDenied(subject, object, action string) error {
+	msg := fmt.Sprintf("%s not permitted to %s to %s", subject, object, action)
+	return status.New(codes.PermissionDenied, msg).Err()
 }
@@ -0,0 +1,46 @@
+package auth_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/siluk00/proglog/internal/auth"
+	"github.com/siluk00/proglog/internal/auth/aclgen"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestCasbinAuthorizer(t *testing.T) {
+	modelFile, policyFile := aclgen.NewTestACL(t)
+	authorizer, err := auth.New(modelFile, policyFile)
+	require.NoError(t, err)
+
+	require.NoError(t, authorizer.Authorize("root", "*", "produce"))
+
+	err = authorizer.Authorize("nobody", "*", "produce")
+	require.Equal(t, codes.PermissionDenied, status.Code(err))
+}
+
+const opaPolicy = `package proglog.authz
+
+default allow := false
+
+allow {
+	input.subject == "root"
+}
+`
+
+func TestOPAAuthorizer(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policy.rego")
+	require.NoError(t, os.WriteFile(path, []byte(opaPolicy), 0o644))
+
+	authorizer, err := auth.NewOPA(path)
+	require.NoError(t, err)
+
+	require.NoError(t, authorizer.Authorize("root", "*", "produce"))
+
+	err = authorizer.Authorize("nobody", "*", "produce")
+	require.Equal(t, codes.PermissionDenied, status.Code(err))
+}
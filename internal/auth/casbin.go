@@ -0,0 +1,31 @@
+package auth
+
+import "github.com/casbin/casbin/v2"
+
+// CasbinAuthorizer is the original Authorizer backend: an ACL enforced by
+// Casbin from a model/policy file pair.
+type CasbinAuthorizer struct {
+	enforcer *casbin.Enforcer
+}
+
+var _ Authorizer = (*CasbinAuthorizer)(nil)
+
+// New builds a CasbinAuthorizer from a Casbin model and policy file.
+func New(model, policy string) (*CasbinAuthorizer, error) {
+	e, err := casbin.NewEnforcer(model, policy)
+	if err != nil {
+		return nil, err
+	}
+	return &CasbinAuthorizer{enforcer: e}, nil
+}
+
+func (a *CasbinAuthorizer) Authorize(subject, object, action string) error {
+	enforced, err := a.enforcer.Enforce(subject, object, action)
+	if err != nil {
+		return err
+	}
+	if !enforced {
+		return permissionDenied(subject, object, action)
+	}
+	return nil
+}
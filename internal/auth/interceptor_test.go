@@ -0,0 +1,63 @@
+package auth_test
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"testing"
+
+	"github.com/siluk00/proglog/internal/auth"
+	"github.com/siluk00/proglog/internal/config/certgen"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+func TestAuthenticate_NoPeer(t *testing.T) {
+	_, err := auth.Authenticate(context.Background())
+	require.Equal(t, codes.Unknown, status.Code(err))
+}
+
+func TestAuthenticate_NoAuthInfo(t *testing.T) {
+	ctx := peer.NewContext(context.Background(), &peer.Peer{})
+	ctx, err := auth.Authenticate(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "", auth.Subject(ctx))
+}
+
+// A server with an optional client CA accepts TLS connections that never
+// present a client certificate, so AuthInfo is set but VerifiedChains is
+// empty. Authenticate must not panic on that shape (regression test).
+func TestAuthenticate_NoVerifiedChains(t *testing.T) {
+	ctx := peer.NewContext(context.Background(), &peer.Peer{
+		AuthInfo: credentials.TLSInfo{State: tls.ConnectionState{}},
+	})
+	ctx, err := auth.Authenticate(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "", auth.Subject(ctx))
+}
+
+func TestAuthenticate_VerifiedChain(t *testing.T) {
+	ca := certgen.NewTestCA(t)
+	cert := ca.IssueClient("root")
+
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	require.NoError(t, err)
+
+	ctx := peer.NewContext(context.Background(), &peer.Peer{
+		AuthInfo: credentials.TLSInfo{
+			State: tls.ConnectionState{
+				VerifiedChains: [][]*x509.Certificate{{leaf}},
+			},
+		},
+	})
+	ctx, err = auth.Authenticate(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "root", auth.Subject(ctx))
+}
+
+func TestSubject_Unset(t *testing.T) {
+	require.Equal(t, "", auth.Subject(context.Background()))
+}
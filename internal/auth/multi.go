@@ -0,0 +1,60 @@
+package auth
+
+// CombineMode picks how MultiAuthorizer folds its backends' decisions into
+// one.
+type CombineMode int
+
+const (
+	// All requires every backend to allow the request (logical AND). This
+	// is the safer default: layering a second backend in All mode can only
+	// narrow what's permitted.
+	All CombineMode = iota
+	// Any allows the request if at least one backend allows it (logical
+	// OR), useful when backends cover disjoint subjects (e.g. one per
+	// cluster) rather than layering stricter policy on top of looser.
+	Any
+)
+
+// MultiAuthorizer combines several Authorizers into one decision according
+// to Mode. It's how a deployment runs, say, Casbin and OPA side by side
+// during a migration rather than picking one at a time.
+type MultiAuthorizer struct {
+	Mode        CombineMode
+	Authorizers []Authorizer
+}
+
+var _ Authorizer = (*MultiAuthorizer)(nil)
+
+func (m *MultiAuthorizer) Authorize(subject, object, action string) error {
+	var lastErr error
+	for _, a := range m.Authorizers {
+		err := a.Authorize(subject, object, action)
+		switch m.Mode {
+		case Any:
+			if err == nil {
+				return nil
+			}
+			lastErr = err
+		default: // All
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	if m.Mode == Any {
+		if lastErr == nil {
+			// No authorizers configured; fail closed rather than allow by
+			// default.
+			return permissionDenied(subject, object, action)
+		}
+		return lastErr
+	}
+
+	if len(m.Authorizers) == 0 {
+		// Same reasoning as the Any branch above: an unconfigured
+		// MultiAuthorizer must deny, not silently authorize everything.
+		return permissionDenied(subject, object, action)
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package auth_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/siluk00/proglog/internal/auth"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// stubAuthorizer always returns err, whatever the request.
+type stubAuthorizer struct{ err error }
+
+func (s stubAuthorizer) Authorize(subject, object, action string) error { return s.err }
+
+var errDenied = errors.New("denied")
+
+func TestMultiAuthorizer_All(t *testing.T) {
+	allow := stubAuthorizer{}
+	deny := stubAuthorizer{err: errDenied}
+
+	require.NoError(t, (&auth.MultiAuthorizer{
+		Mode:        auth.All,
+		Authorizers: []auth.Authorizer{allow, allow},
+	}).Authorize("root", "*", "produce"))
+
+	require.Equal(t, errDenied, (&auth.MultiAuthorizer{
+		Mode:        auth.All,
+		Authorizers: []auth.Authorizer{allow, deny},
+	}).Authorize("root", "*", "produce"))
+}
+
+func TestMultiAuthorizer_Any(t *testing.T) {
+	allow := stubAuthorizer{}
+	deny := stubAuthorizer{err: errDenied}
+
+	require.NoError(t, (&auth.MultiAuthorizer{
+		Mode:        auth.Any,
+		Authorizers: []auth.Authorizer{deny, allow},
+	}).Authorize("root", "*", "produce"))
+
+	err := (&auth.MultiAuthorizer{
+		Mode:        auth.Any,
+		Authorizers: []auth.Authorizer{deny, deny},
+	}).Authorize("root", "*", "produce")
+	require.Equal(t, errDenied, err)
+}
+
+// An unconfigured MultiAuthorizer must deny, not silently authorize
+// everything, regardless of which Mode it's left at.
+func TestMultiAuthorizer_NoBackendsFailsClosed(t *testing.T) {
+	for _, mode := range []auth.CombineMode{auth.All, auth.Any} {
+		err := (&auth.MultiAuthorizer{Mode: mode}).Authorize("root", "*", "produce")
+		require.Equal(t, codes.PermissionDenied, status.Code(err))
+	}
+}
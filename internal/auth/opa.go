@@ -0,0 +1,51 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/open-policy-agent/opa/rego"
+)
+
+// opaAllowQuery is the Rego query OPAAuthorizer runs: policies are expected
+// to define an "allow" rule under package proglog.authz, taking subject,
+// object and action as input and producing a boolean.
+const opaAllowQuery = "data.proglog.authz.allow"
+
+// OPAAuthorizer is an Authorizer backend that evaluates a Rego policy
+// bundle via github.com/open-policy-agent/opa/rego, as an alternative to
+// the Casbin-based CasbinAuthorizer.
+type OPAAuthorizer struct {
+	query rego.PreparedEvalQuery
+}
+
+var _ Authorizer = (*OPAAuthorizer)(nil)
+
+// NewOPA compiles the Rego policy at bundlePath - a single .rego file or a
+// bundle directory - into a prepared query against opaAllowQuery.
+func NewOPA(bundlePath string) (*OPAAuthorizer, error) {
+	query, err := rego.New(
+		rego.Query(opaAllowQuery),
+		rego.Load([]string{bundlePath}, nil),
+	).PrepareForEval(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("preparing rego policy %q: %w", bundlePath, err)
+	}
+	return &OPAAuthorizer{query: query}, nil
+}
+
+func (a *OPAAuthorizer) Authorize(subject, object, action string) error {
+	results, err := a.query.Eval(context.Background(), rego.EvalInput(map[string]any{
+		"subject": subject,
+		"object":  object,
+		"action":  action,
+	}))
+	if err != nil {
+		return err
+	}
+
+	if !results.Allowed() {
+		return permissionDenied(subject, object, action)
+	}
+	return nil
+}
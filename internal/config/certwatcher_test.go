@@ -0,0 +1,92 @@
+package config_test
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/siluk00/proglog/internal/config"
+	"github.com/siluk00/proglog/internal/config/certgen"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/credentials"
+)
+
+// TestDynamicTLSCredentials_CARotation is the actual promise of this
+// package: a long-lived server keeps verifying client certs across a CA
+// rotation on disk, with no restart. It starts a watcher against a real
+// CA file, serves real TLS handshakes off it, rewrites the file to a new
+// CA, and asserts a cert signed by the old CA is rejected while one freshly
+// issued by the new CA is accepted.
+func TestDynamicTLSCredentials_CARotation(t *testing.T) {
+	serverCA := certgen.NewTestCA(t)
+	clientCAv1 := certgen.NewTestCA(t)
+
+	caFile := filepath.Join(t.TempDir(), "client-ca.pem")
+	require.NoError(t, os.WriteFile(caFile, clientCAv1.CertPEM(), 0o644))
+
+	watcher, err := config.NewCertPoolWatcher(caFile)
+	require.NoError(t, err)
+	defer watcher.Close()
+
+	serverCreds := config.NewDynamicTLSCredentials(&tls.Config{
+		Certificates: []tls.Certificate{serverCA.IssueServer()},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		// TLS 1.3's half-RTT data lets a client finish its handshake before
+		// the server has verified the client cert, so a rejection would
+		// only surface on the next read/write rather than from
+		// ClientHandshake itself. Pin 1.2 so the rotation test observes
+		// verification failures synchronously, same as the handshake calls
+		// below assert on.
+		MaxVersion: tls.VersionTLS12,
+	}, watcher)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				_, _, _ = serverCreds.ServerHandshake(conn)
+			}()
+		}
+	}()
+
+	handshake := func(cert tls.Certificate) error {
+		conn, err := net.Dial("tcp", ln.Addr().String())
+		require.NoError(t, err)
+		defer conn.Close()
+
+		clientCreds := credentials.NewTLS(&tls.Config{
+			Certificates: []tls.Certificate{cert},
+			RootCAs:      serverCA.RootPool(),
+			MaxVersion:   tls.VersionTLS12,
+		})
+		_, _, err = clientCreds.ClientHandshake(context.Background(), "127.0.0.1", conn)
+		return err
+	}
+
+	oldLeaf := clientCAv1.IssueClient("node-1")
+	require.NoError(t, handshake(oldLeaf), "leaf signed by the CA on disk should be accepted")
+
+	clientCAv2 := certgen.NewTestCA(t)
+	require.NoError(t, os.WriteFile(caFile, clientCAv2.CertPEM(), 0o644))
+
+	require.Eventually(t, func() bool {
+		return handshake(oldLeaf) != nil
+	}, 2*time.Second, 10*time.Millisecond, "leaf signed by the rotated-out CA should eventually be rejected")
+
+	newLeaf := clientCAv2.IssueClient("node-2")
+	require.Eventually(t, func() bool {
+		return handshake(newLeaf) == nil
+	}, 2*time.Second, 10*time.Millisecond, "leaf signed by the newly-written CA should be accepted without a restart")
+}
@@ -0,0 +1,34 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// CONFIG_DIR lets operators point proglog at a directory of certs other
+// than ~/.proglog, primarily so tests can isolate themselves with
+// t.TempDir().
+var CONFIG_DIR = os.Getenv("PROGLOG_CONFIG_DIR")
+
+var (
+	CAFile               = configFile("ca.pem")
+	ServerCertFile       = configFile("server.pem")
+	ServerKeyFile        = configFile("server-key.pem")
+	RootClientCertFile   = configFile("root-client.pem")
+	RootClientKeyFile    = configFile("root-client-key.pem")
+	NobodyClientCertFile = configFile("nobody-client.pem")
+	NobodyClientKeyFile  = configFile("nobody-client-key.pem")
+	ACLModelFile         = configFile("model.conf")
+	ACLPolicyFile        = configFile("policy.csv")
+)
+
+func configFile(filename string) string {
+	if CONFIG_DIR != "" {
+		return filepath.Join(CONFIG_DIR, filename)
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		panic(err)
+	}
+	return filepath.Join(homeDir, ".proglog", filename)
+}
@@ -0,0 +1,75 @@
+package config
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+)
+
+// TLSConfig describes the material needed to build a *tls.Config for any of
+// the three roles a proglog node's certs can play. A server cert always
+// needs CertFile/KeyFile and verifies clients against CAFile when one is
+// given. A client cert needs CAFile to verify the server and only needs
+// CertFile/KeyFile when the server requires mutual TLS. A peer cert - used
+// for node-to-node RPCs like replication and Raft, where each node is both
+// a client and a server to the others - carries both the ServerAuth and
+// ClientAuth EKUs, so it presents CertFile/KeyFile like a server but also
+// verifies the far end against CAFile like a client.
+type TLSConfig struct {
+	CertFile      string
+	KeyFile       string
+	CAFile        string
+	ServerAddress string
+	Server        bool
+	Peer          bool
+}
+
+func SetupTLSConfig(cfg TLSConfig) (*tls.Config, error) {
+	var err error
+	tlsConfig := &tls.Config{}
+
+	if cfg.CertFile != "" && cfg.KeyFile != "" {
+		tlsConfig.Certificates = make([]tls.Certificate, 1)
+		tlsConfig.Certificates[0], err = tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("loading key pair: %w", err)
+		}
+	}
+
+	if cfg.CAFile != "" {
+		ca, err := loadCertPool(cfg.CAFile)
+		if err != nil {
+			return nil, err
+		}
+
+		switch {
+		case cfg.Peer:
+			tlsConfig.ClientCAs = ca
+			tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+			tlsConfig.RootCAs = ca
+		case cfg.Server:
+			tlsConfig.ClientCAs = ca
+			tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+		default:
+			tlsConfig.RootCAs = ca
+		}
+
+		tlsConfig.ServerName = cfg.ServerAddress
+	}
+
+	return tlsConfig, nil
+}
+
+func loadCertPool(caFile string) (*x509.CertPool, error) {
+	b, err := os.ReadFile(caFile)
+	if err != nil {
+		return nil, fmt.Errorf("reading ca file: %w", err)
+	}
+
+	ca := x509.NewCertPool()
+	if ok := ca.AppendCertsFromPEM(b); !ok {
+		return nil, fmt.Errorf("failed to parse root certificate: %q", caFile)
+	}
+	return ca, nil
+}
@@ -0,0 +1,62 @@
+package config
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+
+	"google.golang.org/grpc/credentials"
+)
+
+// dynamicTLSCredentials behaves like credentials.NewTLS(base), except it
+// re-reads the current CA pool from a CertPoolWatcher on every handshake
+// instead of trusting whatever RootCAs/ClientCAs base was built with. This
+// is what lets a long-lived gRPC server or client connection keep working
+// across a CA rotation: the rotation only has to land on disk, the next
+// handshake picks it up.
+type dynamicTLSCredentials struct {
+	base    *tls.Config
+	watcher *CertPoolWatcher
+}
+
+// NewDynamicTLSCredentials wraps base so that, on every handshake, its
+// RootCAs (client role) or ClientCAs (server role) are swapped for
+// watcher's current pool before the TLS handshake runs.
+func NewDynamicTLSCredentials(base *tls.Config, watcher *CertPoolWatcher) credentials.TransportCredentials {
+	return &dynamicTLSCredentials{base: base, watcher: watcher}
+}
+
+// current clones base and refreshes whichever CA field it was using, so
+// concurrent handshakes never share (and race on) the same *tls.Config.
+func (c *dynamicTLSCredentials) current() *tls.Config {
+	cfg := c.base.Clone()
+	pool := c.watcher.Pool()
+	if cfg.ClientAuth == tls.RequireAndVerifyClientCert {
+		cfg.ClientCAs = pool
+	}
+	if cfg.RootCAs != nil {
+		cfg.RootCAs = pool
+	}
+	return cfg
+}
+
+func (c *dynamicTLSCredentials) ClientHandshake(ctx context.Context, authority string, rawConn net.Conn) (net.Conn, credentials.AuthInfo, error) {
+	return credentials.NewTLS(c.current()).ClientHandshake(ctx, authority, rawConn)
+}
+
+func (c *dynamicTLSCredentials) ServerHandshake(rawConn net.Conn) (net.Conn, credentials.AuthInfo, error) {
+	return credentials.NewTLS(c.current()).ServerHandshake(rawConn)
+}
+
+func (c *dynamicTLSCredentials) Info() credentials.ProtocolInfo {
+	return credentials.NewTLS(c.base).Info()
+}
+
+func (c *dynamicTLSCredentials) Clone() credentials.TransportCredentials {
+	return &dynamicTLSCredentials{base: c.base.Clone(), watcher: c.watcher}
+}
+
+func (c *dynamicTLSCredentials) OverrideServerName(name string) error {
+	c.base.ServerName = name
+	return nil
+}
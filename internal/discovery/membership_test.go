@@ -0,0 +1,117 @@
+package discovery_test
+
+import (
+	"fmt"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/siluk00/proglog/internal/discovery"
+
+	"github.com/stretchr/testify/require"
+)
+
+// handler is a discovery.Handler that just records what it's told, so tests
+// can assert on join/leave events without a real Handler's side effects
+// (e.g. a DistributedLog adding/removing Raft voters) in the way.
+type handler struct {
+	mu     sync.Mutex
+	joins  map[string]string
+	leaves map[string]struct{}
+}
+
+func newHandler() *handler {
+	return &handler{
+		joins:  make(map[string]string),
+		leaves: make(map[string]struct{}),
+	}
+}
+
+func (h *handler) Join(name, addr string) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.joins[name] = addr
+	return nil
+}
+
+func (h *handler) Leave(name string) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.joins, name)
+	h.leaves[name] = struct{}{}
+	return nil
+}
+
+func (h *handler) hasJoined(name string) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, ok := h.joins[name]
+	return ok
+}
+
+func (h *handler) hasLeft(name string) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, ok := h.leaves[name]
+	return ok
+}
+
+// freeAddr reserves an ephemeral port by briefly listening on it, then
+// closes the listener so Serf's own memberlist transport can bind it.
+func freeAddr(t *testing.T) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := ln.Addr().String()
+	require.NoError(t, ln.Close())
+	return addr
+}
+
+func newMember(t *testing.T, h discovery.Handler, startJoinAddrs []string) (*discovery.Membership, string) {
+	t.Helper()
+	addr := freeAddr(t)
+	m, err := discovery.New(h, discovery.Config{
+		NodeName:       addr,
+		BindAddr:       addr,
+		Tags:           map[string]string{"rpc_addr": addr},
+		StartJoinAddrs: startJoinAddrs,
+	})
+	require.NoError(t, err)
+	return m, addr
+}
+
+// TestMembership_SurvivesPeerLeave is a regression test for a bug where
+// Membership's eventHandler goroutine exited the moment it processed a
+// member's own leave/fail event, instead of looping to handle the rest of
+// the cluster's membership changes. It joins two Memberships, has the
+// second one leave, and then joins a third member to prove the first
+// member's event loop is still running afterwards.
+func TestMembership_SurvivesPeerLeave(t *testing.T) {
+	h0 := newHandler()
+	m0, addr0 := newMember(t, h0, nil)
+	defer m0.Leave()
+
+	h1 := newHandler()
+	m1, addr1 := newMember(t, h1, []string{addr0})
+	defer m1.Leave()
+
+	require.Eventually(t, func() bool {
+		return h0.hasJoined(addr1)
+	}, 3*time.Second, 100*time.Millisecond, "node 0 should see node 1 join")
+
+	require.NoError(t, m1.Leave())
+
+	require.Eventually(t, func() bool {
+		return h0.hasLeft(addr1)
+	}, 3*time.Second, 100*time.Millisecond, "node 0 should see node 1 leave")
+
+	h2 := newHandler()
+	m2, addr2 := newMember(t, h2, []string{addr0})
+	defer m2.Leave()
+
+	require.Eventually(t, func() bool {
+		return h0.hasJoined(addr2)
+	}, 3*time.Second, 100*time.Millisecond,
+		fmt.Sprintf("node 0's event loop should still be running and see node 2 (%s) join after node 1 left", addr2))
+}
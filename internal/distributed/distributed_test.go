@@ -0,0 +1,95 @@
+package distributed_test
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"testing"
+	"time"
+
+	api "github.com/siluk00/proglog/api/v1"
+	. "github.com/siluk00/proglog/internal/distributed"
+
+	"github.com/hashicorp/raft"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMultipleNodes(t *testing.T) {
+	var logs []*DistributedLog
+	nodeCount := 3
+
+	for i := 0; i < nodeCount; i++ {
+		dataDir, err := os.MkdirTemp("", "distributed-log-test")
+		require.NoError(t, err)
+		defer func() {
+			_ = os.RemoveAll(dataDir)
+		}()
+
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+
+		config := Config{}
+		config.Raft.StreamLayer = NewStreamLayer(ln, nil, nil)
+		config.Raft.LocalID = raft.ServerID(fmt.Sprintf("%d", i))
+		config.Raft.HeartbeatTimeout = 50 * time.Millisecond
+		config.Raft.ElectionTimeout = 50 * time.Millisecond
+		config.Raft.LeaderLeaseTimeout = 50 * time.Millisecond
+		config.Raft.CommitTimeout = 5 * time.Millisecond
+		if i == 0 {
+			config.Raft.Bootstrap = true
+		}
+
+		l, err := NewDistributedLog(dataDir, config)
+		require.NoError(t, err)
+
+		if i != 0 {
+			err = logs[0].Join(
+				fmt.Sprintf("%d", i),
+				ln.Addr().String(),
+			)
+			require.NoError(t, err)
+		} else {
+			err = l.WaitForLeader(3 * time.Second)
+			require.NoError(t, err)
+		}
+
+		logs = append(logs, l)
+	}
+
+	records := []*api.Record{
+		{Value: []byte("first")},
+		{Value: []byte("second")},
+	}
+
+	for _, record := range records {
+		off, err := logs[0].Append(record)
+		require.NoError(t, err)
+
+		require.Eventually(t, func() bool {
+			for _, l := range logs {
+				got, err := l.Read(off)
+				if err != nil {
+					return false
+				}
+				if string(got.Value) != string(record.Value) {
+					return false
+				}
+			}
+			return true
+		}, 500*time.Millisecond, 50*time.Millisecond)
+	}
+
+	require.NoError(t, logs[0].Leave("1"))
+	time.Sleep(50 * time.Millisecond)
+
+	off, err := logs[0].Append(&api.Record{Value: []byte("third")})
+	require.NoError(t, err)
+
+	time.Sleep(50 * time.Millisecond)
+	_, err = logs[1].Read(off)
+	require.Error(t, err)
+
+	got, err := logs[2].Read(off)
+	require.NoError(t, err)
+	require.Equal(t, []byte("third"), got.Value)
+}
@@ -0,0 +1,93 @@
+package distributed
+
+import (
+	"bytes"
+	"crypto/tls"
+	"errors"
+	"net"
+	"time"
+
+	"github.com/hashicorp/raft"
+)
+
+var errNotRaftRPC = errors.New("not a raft rpc")
+
+// RaftRPC is the one-byte prefix proglog writes on every TCP connection
+// destined for Raft. A caller that wants to share one listener between
+// gRPC and Raft traffic (e.g. via cmux) can match on this byte to route
+// connections to the StreamLayer instead of the gRPC server.
+const RaftRPC = 1
+
+var _ raft.StreamLayer = (*StreamLayer)(nil)
+
+// StreamLayer adapts a net.Listener into a raft.StreamLayer, wrapping
+// outgoing dials with the RaftRPC byte prefix and, when TLS is configured,
+// a mutually-authenticated TLS handshake.
+type StreamLayer struct {
+	ln              net.Listener
+	serverTLSConfig *tls.Config
+	peerTLSConfig   *tls.Config
+}
+
+func NewStreamLayer(
+	ln net.Listener,
+	serverTLSConfig,
+	peerTLSConfig *tls.Config,
+) *StreamLayer {
+	return &StreamLayer{
+		ln:              ln,
+		serverTLSConfig: serverTLSConfig,
+		peerTLSConfig:   peerTLSConfig,
+	}
+}
+
+func (s *StreamLayer) Dial(addr raft.ServerAddress, timeout time.Duration) (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: timeout}
+	var conn, err = dialer.Dial("tcp", string(addr))
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := conn.Write([]byte{RaftRPC}); err != nil {
+		return nil, err
+	}
+
+	if s.peerTLSConfig != nil {
+		conn = tls.Client(conn, s.peerTLSConfig)
+	}
+
+	return conn, nil
+}
+
+// Accept waits for the next incoming connection and reads off the RaftRPC
+// byte the dialer wrote. A connection that starts with any other byte is
+// returned as errNotRaftRPC; routing non-Raft traffic elsewhere is up to
+// the caller's own mux, ahead of this listener.
+func (s *StreamLayer) Accept() (net.Conn, error) {
+	conn, err := s.ln.Accept()
+	if err != nil {
+		return nil, err
+	}
+
+	b := make([]byte, 1)
+	if _, err = conn.Read(b); err != nil {
+		return nil, err
+	}
+	if bytes.Compare([]byte{RaftRPC}, b) != 0 {
+		return nil, errNotRaftRPC
+	}
+
+	if s.serverTLSConfig != nil {
+		return tls.Server(conn, s.serverTLSConfig), nil
+	}
+
+	return conn, nil
+}
+
+func (s *StreamLayer) Close() error {
+	return s.ln.Close()
+}
+
+func (s *StreamLayer) Addr() net.Addr {
+	return s.ln.Addr()
+}
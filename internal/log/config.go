@@ -0,0 +1,11 @@
+package log
+
+// Config configures the size limits a Log's segments are allowed to grow to
+// before a new segment is rolled.
+type Config struct {
+	Segment struct {
+		MaxStoreBytes uint64
+		MaxIndexBytes uint64
+		InitialOffset uint64
+	}
+}
@@ -0,0 +1,154 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"os"
+	"testing"
+
+	api "github.com/siluk00/proglog/api/v1"
+	"github.com/siluk00/proglog/internal/auth"
+	"github.com/siluk00/proglog/internal/auth/aclgen"
+	"github.com/siluk00/proglog/internal/config/certgen"
+	"github.com/siluk00/proglog/internal/log"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/status"
+)
+
+// nonLeaderLog satisfies both CommitLog and leaderForwarder, reporting a
+// fixed leader address and never actually appending - standing in for a
+// distributed.DistributedLog on a follower node, whose every Produce
+// forwards to the leader instead of appending locally.
+type nonLeaderLog struct {
+	CommitLog
+	leaderAddr string
+}
+
+func (l *nonLeaderLog) IsLeader() bool { return false }
+func (l *nonLeaderLog) Leader() string { return l.leaderAddr }
+
+var _ leaderForwarder = (*nonLeaderLog)(nil)
+
+// forwardingCluster starts two grpc servers sharing one CA: a "leader"
+// with a plain CommitLog, and a "follower" whose CommitLog always reports
+// it isn't the leader, so every Produce it gets forwards to the leader
+// over peerCN's cert. It's the integration path forwardProduce exists for,
+// which neither internal/distributed (no gRPC server) nor the rest of
+// internal/server (a plain, non-forwarding CommitLog) exercises.
+func forwardingCluster(t *testing.T, ca *certgen.TestCA, policy, peerCN string) (
+	followerClient api.LogClient, leaderClient api.LogClient, teardown func(),
+) {
+	t.Helper()
+
+	modelFile, policyFile := aclgen.NewTestACLWithPolicy(t, policy)
+	authorizer, err := auth.New(modelFile, policyFile)
+	require.NoError(t, err)
+
+	serverTLSConfig := &tls.Config{
+		Certificates: []tls.Certificate{ca.IssueServer()},
+		ClientCAs:    ca.RootPool(),
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+	}
+
+	newDir := func() string {
+		dir, err := os.MkdirTemp("", "forward-test")
+		require.NoError(t, err)
+		return dir
+	}
+
+	leaderLog, err := log.NewLog(newDir(), log.Config{})
+	require.NoError(t, err)
+
+	leaderLn, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	leaderSrv, err := NewGRPCServer(&Config{
+		CommitLog:  leaderLog,
+		Authorizer: authorizer,
+	}, grpc.Creds(credentials.NewTLS(serverTLSConfig)))
+	require.NoError(t, err)
+	go leaderSrv.Serve(leaderLn)
+
+	peerCreds := credentials.NewTLS(&tls.Config{
+		Certificates: []tls.Certificate{ca.IssueClient(peerCN)},
+		RootCAs:      ca.RootPool(),
+		ServerName:   "127.0.0.1",
+	})
+	followerLog := &nonLeaderLog{
+		CommitLog:  leaderLog,
+		leaderAddr: leaderLn.Addr().String(),
+	}
+	followerLn, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	followerSrv, err := NewGRPCServer(&Config{
+		CommitLog:         followerLog,
+		Authorizer:        authorizer,
+		PeerClientOptions: []grpc.DialOption{grpc.WithTransportCredentials(peerCreds)},
+	}, grpc.Creds(credentials.NewTLS(serverTLSConfig)))
+	require.NoError(t, err)
+	go followerSrv.Serve(followerLn)
+
+	newClient := func(addr, cn string) api.LogClient {
+		conn, err := grpc.NewClient(addr, grpc.WithTransportCredentials(credentials.NewTLS(&tls.Config{
+			Certificates: []tls.Certificate{ca.IssueClient(cn)},
+			RootCAs:      ca.RootPool(),
+			ServerName:   "127.0.0.1",
+		})))
+		require.NoError(t, err)
+		return api.NewLogClient(conn)
+	}
+
+	return newClient(followerLn.Addr().String(), "client"),
+		newClient(leaderLn.Addr().String(), "client"),
+		func() {
+			followerSrv.Stop()
+			leaderSrv.Stop()
+			followerLn.Close()
+			leaderLn.Close()
+		}
+}
+
+func TestForwardProduce(t *testing.T) {
+	ca := certgen.NewTestCA(t)
+	// Both "client" (authorized at the follower it dials) and "peer" (the
+	// identity the leader actually sees the forwarded call under) need
+	// produce rights for the write to go through end to end.
+	policy := "p, client, *, produce\np, client, *, consume\np, peer, *, produce\n"
+
+	followerClient, leaderClient, teardown := forwardingCluster(t, ca, policy, "peer")
+	defer teardown()
+
+	ctx := context.Background()
+	want := &api.Record{Value: []byte("forwarded")}
+
+	produce, err := followerClient.Produce(ctx, &api.ProduceRequest{Record: want})
+	require.NoError(t, err)
+
+	consume, err := leaderClient.Consume(ctx, &api.ConsumeRequest{Offset: produce.Offset})
+	require.NoError(t, err)
+	require.Equal(t, want.Value, consume.Record.Value)
+}
+
+// TestForwardProduce_AuthorizesAsPeerNotClient documents the design gap
+// called out on Config.PeerClientOptions: the leader authorizes a
+// forwarded write as the forwarding peer's CN, not the original client's.
+// Here "client" has every right it needs, but "peer" isn't granted
+// produce, so a write that a direct call to the leader would happily
+// accept is rejected once it's forwarded.
+func TestForwardProduce_AuthorizesAsPeerNotClient(t *testing.T) {
+	ca := certgen.NewTestCA(t)
+	policy := "p, client, *, produce\np, client, *, consume\n"
+
+	followerClient, _, teardown := forwardingCluster(t, ca, policy, "peer")
+	defer teardown()
+
+	_, err := followerClient.Produce(context.Background(), &api.ProduceRequest{
+		Record: &api.Record{Value: []byte("forwarded")},
+	})
+	require.Equal(t, codes.PermissionDenied, status.Code(err))
+}
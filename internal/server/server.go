@@ -0,0 +1,262 @@
+package server
+
+import (
+	"context"
+	"time"
+
+	grpc_auth "github.com/grpc-ecosystem/go-grpc-middleware/auth"
+	api "github.com/siluk00/proglog/api/v1"
+	"github.com/siluk00/proglog/internal/auth"
+	"github.com/siluk00/proglog/internal/telemetry"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	objectWildcard = "*"
+	produceAction  = "produce"
+	consumeAction  = "consume"
+	clusterAction  = "cluster"
+)
+
+// CommitLog is the subset of internal/log.Log the gRPC server depends on,
+// so it can be swapped for a Raft-replicated implementation without the
+// server knowing the difference.
+type CommitLog interface {
+	Append(*api.Record) (uint64, error)
+	Read(uint64) (*api.Record, error)
+}
+
+// leaderForwarder is implemented by CommitLogs that replicate through a
+// consensus protocol, such as internal/distributed.DistributedLog, so the
+// server can transparently forward writes to the current leader instead of
+// rejecting them when it isn't one.
+type leaderForwarder interface {
+	IsLeader() bool
+	Leader() string
+}
+
+// clusterer is implemented by CommitLogs that support adding and removing
+// Raft voters over the wire, such as internal/distributed.DistributedLog,
+// so Join/Leave can report Unimplemented on a plain, non-clustered log
+// instead of panicking.
+type clusterer interface {
+	Join(id, addr string) error
+	Leave(id string) error
+}
+
+type Config struct {
+	CommitLog  CommitLog
+	Authorizer auth.Authorizer
+	// PeerClientOptions configures the gRPC client the server dials the
+	// leader with when forwarding a write. Callers running a distributed
+	// CommitLog over TLS should set this to matching peer credentials.
+	//
+	// The leader authorizes a forwarded Produce using the subject CN on
+	// this connection's peer cert, not the original client's CN - the
+	// hop's identity, not the caller's. Operators must grant that peer
+	// CN produce in the ACL policy on every node, or writes forwarded
+	// from a non-leader will fail with PermissionDenied even though the
+	// original client is allowed to produce.
+	PeerClientOptions []grpc.DialOption
+
+	// TelemetryConfig selects and configures the OpenTelemetry exporter
+	// the server's tracer/meter providers publish through. Nil disables
+	// tracing and metrics.
+	TelemetryConfig *telemetry.Config
+	// Telemetry is populated by NewGRPCServer from TelemetryConfig, and
+	// exposes Shutdown so callers can tear it down alongside the server,
+	// e.g. in Agent.Shutdown or a test's teardown func.
+	Telemetry *telemetry.Telemetry
+}
+
+var _ api.LogServer = (*grpcServer)(nil)
+
+type grpcServer struct {
+	api.UnimplementedLogServer
+	*Config
+}
+
+func newGRPCServer(config *Config) (*grpcServer, error) {
+	return &grpcServer{
+		Config: config,
+	}, nil
+}
+
+// NewGRPCServer builds a *grpc.Server wired up with an authentication
+// interceptor that reads every RPC's subject from the peer's TLS client
+// certificate, for the handlers to authorize, and, when
+// config.TelemetryConfig is set, an OpenTelemetry
+// stats handler that traces and measures every RPC, then registers the Log
+// service on top of it. The Telemetry it builds is stored on config so the
+// caller can shut it down alongside the server.
+func NewGRPCServer(config *Config, opts ...grpc.ServerOption) (*grpc.Server, error) {
+	tel, err := telemetry.New(config.TelemetryConfig)
+	if err != nil {
+		return nil, err
+	}
+	config.Telemetry = tel
+
+	if sh := tel.StatsHandler(); sh != nil {
+		opts = append(opts, grpc.StatsHandler(sh))
+	}
+	opts = append(opts, grpc.ChainUnaryInterceptor(
+		grpc_auth.UnaryServerInterceptor(auth.Authenticate),
+	), grpc.ChainStreamInterceptor(
+		grpc_auth.StreamServerInterceptor(auth.Authenticate),
+	))
+	gsrv := grpc.NewServer(opts...)
+	srv, err := newGRPCServer(config)
+	if err != nil {
+		return nil, err
+	}
+	api.RegisterLogServer(gsrv, srv)
+	return gsrv, nil
+}
+
+func (s *grpcServer) Produce(ctx context.Context, req *api.ProduceRequest) (*api.ProduceResponse, error) {
+	start := time.Now()
+	defer func() { s.Telemetry.ObserveRPC(ctx, "Produce", start) }()
+
+	if err := s.Authorizer.Authorize(
+		auth.Subject(ctx), objectWildcard, produceAction,
+	); err != nil {
+		return nil, err
+	}
+
+	if fwd, ok := s.CommitLog.(leaderForwarder); ok && !fwd.IsLeader() {
+		return s.forwardProduce(ctx, fwd.Leader(), req)
+	}
+
+	offset, err := s.CommitLog.Append(req.Record)
+	if err != nil {
+		s.Telemetry.Logger(ctx, "server").Error("append failed", zap.Error(err))
+		return nil, err
+	}
+	s.Telemetry.AddProduceBytes(ctx, len(req.Record.Value))
+	return &api.ProduceResponse{Offset: offset}, nil
+}
+
+// forwardProduce dials the cluster leader and replays the write there,
+// since only the leader is allowed to append to a Raft-replicated log.
+// The leader authorizes this call using s.PeerClientOptions' cert CN, not
+// the original client's (see Config.PeerClientOptions) - the subject the
+// leader sees has already changed identity by the time it gets here.
+func (s *grpcServer) forwardProduce(ctx context.Context, leaderAddr string, req *api.ProduceRequest) (*api.ProduceResponse, error) {
+	if leaderAddr == "" {
+		return nil, status.New(codes.Unavailable, "no leader available").Err()
+	}
+
+	opts := s.PeerClientOptions
+	if opts == nil {
+		opts = []grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())}
+	}
+	conn, err := grpc.NewClient(leaderAddr, opts...)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	return api.NewLogClient(conn).Produce(ctx, req)
+}
+
+func (s *grpcServer) Consume(ctx context.Context, req *api.ConsumeRequest) (*api.ConsumeResponse, error) {
+	start := time.Now()
+	defer func() { s.Telemetry.ObserveRPC(ctx, "Consume", start) }()
+
+	if err := s.Authorizer.Authorize(
+		auth.Subject(ctx), objectWildcard, consumeAction,
+	); err != nil {
+		return nil, err
+	}
+
+	record, err := s.CommitLog.Read(req.Offset)
+	if err != nil {
+		if _, ok := err.(api.ErrOffsetOutOfRange); !ok {
+			s.Telemetry.Logger(ctx, "server").Error("read failed", zap.Error(err))
+		}
+		return nil, err
+	}
+	s.Telemetry.AddConsumeBytes(ctx, len(record.Value))
+	return &api.ConsumeResponse{Record: record}, nil
+}
+
+// Join adds the node at req.Addr, identified by req.Id, to the Raft
+// cluster backing this server's CommitLog, so operators can grow a
+// cluster over the wire rather than needing in-process access to it.
+func (s *grpcServer) Join(ctx context.Context, req *api.JoinRequest) (*api.JoinResponse, error) {
+	if err := s.Authorizer.Authorize(
+		auth.Subject(ctx), objectWildcard, clusterAction,
+	); err != nil {
+		return nil, err
+	}
+
+	c, ok := s.CommitLog.(clusterer)
+	if !ok {
+		return nil, status.New(codes.Unimplemented, "commit log does not support cluster membership changes").Err()
+	}
+	if err := c.Join(req.Id, req.Addr); err != nil {
+		return nil, err
+	}
+	return &api.JoinResponse{}, nil
+}
+
+// Leave removes the node identified by req.Id from the Raft cluster
+// backing this server's CommitLog.
+func (s *grpcServer) Leave(ctx context.Context, req *api.LeaveRequest) (*api.LeaveResponse, error) {
+	if err := s.Authorizer.Authorize(
+		auth.Subject(ctx), objectWildcard, clusterAction,
+	); err != nil {
+		return nil, err
+	}
+
+	c, ok := s.CommitLog.(clusterer)
+	if !ok {
+		return nil, status.New(codes.Unimplemented, "commit log does not support cluster membership changes").Err()
+	}
+	if err := c.Leave(req.Id); err != nil {
+		return nil, err
+	}
+	return &api.LeaveResponse{}, nil
+}
+
+func (s *grpcServer) ProduceStream(stream grpc.BidiStreamingServer[api.ProduceRequest, api.ProduceResponse]) error {
+	for {
+		req, err := stream.Recv()
+		if err != nil {
+			return err
+		}
+		res, err := s.Produce(stream.Context(), req)
+		if err != nil {
+			return err
+		}
+		if err = stream.Send(res); err != nil {
+			return err
+		}
+	}
+}
+
+func (s *grpcServer) ConsumeStream(req *api.ConsumeRequest, stream grpc.ServerStreamingServer[api.ConsumeResponse]) error {
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		default:
+			res, err := s.Consume(stream.Context(), req)
+			switch err.(type) {
+			case nil:
+			case api.ErrOffsetOutOfRange:
+				continue
+			default:
+				return err
+			}
+			if err = stream.Send(res); err != nil {
+				return err
+			}
+			req.Offset++
+		}
+	}
+}
@@ -2,18 +2,19 @@ package server
 
 import (
 	"context"
+	"crypto/tls"
 	"flag"
 	"net"
 	"os"
 	"testing"
-	"time"
 
 	api "github.com/siluk00/proglog/api/v1"
 	"github.com/siluk00/proglog/internal/auth"
-	"github.com/siluk00/proglog/internal/config"
+	"github.com/siluk00/proglog/internal/auth/aclgen"
+	"github.com/siluk00/proglog/internal/config/certgen"
 	"github.com/siluk00/proglog/internal/log"
+	"github.com/siluk00/proglog/internal/telemetry"
 	"github.com/stretchr/testify/require"
-	"go.opencensus.io/examples/exporter"
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
@@ -46,6 +47,7 @@ func TestServer(t *testing.T) {
 		"produce/consume streaam succeeds":                   testProduceConsumeStream,
 		"consume paast log boundary fails":                   testConsumePastBoundary,
 		"unauthorized fails":                                 testUnathorized,
+		"join on a non-clustered log is unimplemented":       testJoinUnimplemented,
 	} {
 		t.Run(scenario, func(t *testing.T) {
 			rootClient, nobodyClient, config, teardown := setupTest(t, nil)
@@ -70,16 +72,18 @@ func setupTest(t *testing.T, fn func(*Config)) (
 	l, err := net.Listen("tcp", "127.0.0.1:0")
 	require.NoError(t, err)
 
+	// Mint a CA and leaf certs for this test in-process instead of reading
+	// fixtures off disk, so tests can use arbitrary CNs (root/nobody below,
+	// and whatever per-CN authorizer tests need).
+	ca := certgen.NewTestCA(t)
+
 	// Helper function that allows to create a grpc client with specific TLS credentials
-	newClient := func(crtPath, keyPath string) (*grpc.ClientConn, api.LogClient, []grpc.DialOption) {
-		// Client certificate, client private key, CAFile to verify server
-		tlsConfig, err := config.SetupTLSConfig(config.TLSConfig{
-			CertFile: crtPath,
-			KeyFile:  keyPath,
-			CAFile:   config.CAFile,
-			Server:   false,
-		})
-		require.NoError(t, err)
+	newClient := func(cn string) (*grpc.ClientConn, api.LogClient, []grpc.DialOption) {
+		tlsConfig := &tls.Config{
+			Certificates: []tls.Certificate{ca.IssueClient(cn)},
+			RootCAs:      ca.RootPool(),
+			ServerName:   "127.0.0.1",
+		}
 		// Wraps the tls.config to grpc-ready credentials
 		tlsCreds := credentials.NewTLS(tlsConfig)
 		// Configure dial options: using tls for secure connection
@@ -95,20 +99,17 @@ func setupTest(t *testing.T, fn func(*Config)) (
 
 	// create root client that has admin privileges
 	var rootConn *grpc.ClientConn
-	rootConn, rootClient, _ = newClient(config.RootClientCertFile, config.RootClientKeyFile)
+	rootConn, rootClient, _ = newClient("root")
 	//create nobody client with no privileges
 	var nobodyConn *grpc.ClientConn
-	nobodyConn, nobodyClient, _ = newClient(config.NobodyClientCertFile, config.NobodyClientKeyFile)
+	nobodyConn, nobodyClient, _ = newClient("nobody")
 
 	// configure TLS for the server
-	serverTLSConfig, err := config.SetupTLSConfig(config.TLSConfig{
-		CertFile:      config.ServerCertFile,
-		KeyFile:       config.ServerKeyFile,
-		CAFile:        config.CAFile,
-		ServerAddress: l.Addr().String(),
-		Server:        true,
-	})
-	require.NoError(t, err)
+	serverTLSConfig := &tls.Config{
+		Certificates: []tls.Certificate{ca.IssueServer()},
+		ClientCAs:    ca.RootPool(),
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+	}
 	// wraps configuration into tls credentials
 	serverCreds := credentials.NewTLS(serverTLSConfig)
 
@@ -120,38 +121,17 @@ func setupTest(t *testing.T, fn func(*Config)) (
 	clog, err := log.NewLog(dir, log.Config{})
 	require.NoError(t, err)
 
-	// Initialize the authorizer
-	// Use casbin model and policy files
-	authorizer, err := auth.New(config.ACLModelFile, config.ACLPolicyFile)
+	// Initialize the authorizer against an in-process Casbin model/policy
+	// pair rather than fixtures expected to already exist on disk.
+	modelFile, policyFile := aclgen.NewTestACL(t)
+	authorizer, err := auth.New(modelFile, policyFile)
 	require.NoError(t, err)
 
-	// Sets up telemetry exporter for debugging
-	var telemetryExporter *exporter.LogExporter
-	if *debug {
-		metricsLogFile, err := os.CreateTemp("", "metrics-*.log")
-		require.NoError(t, err)
-		t.Logf("metrics log file: %s", metricsLogFile.Name())
-
-		tracesLogFile, err := os.CreateTemp("", "traces-*.log")
-		require.NoError(t, err)
-		t.Logf("traces log file: %s", tracesLogFile.Name())
-
-		telemetryExporter, err := exporter.NewLogExporter(
-			exporter.Options{
-				MetricsLogFile:    metricsLogFile.Name(),
-				TracesLogFile:     tracesLogFile.Name(),
-				ReportingInterval: time.Second,
-			},
-		)
-		require.NoError(t, err)
-		err = telemetryExporter.Start()
-		require.NoError(t, err)
-	}
-
 	// Build server configuration
 	cfg = &Config{
-		CommitLog:  clog,
-		Authorizer: authorizer,
+		CommitLog:       clog,
+		Authorizer:      authorizer,
+		TelemetryConfig: telemetryConfigForTest(t),
 	}
 	// aply any custom configurations
 	if fn != nil {
@@ -160,6 +140,9 @@ func setupTest(t *testing.T, fn func(*Config)) (
 	// creates a grpc server with tls credentials
 	server, err := NewGRPCServer(cfg, grpc.Creds(serverCreds))
 	require.NoError(t, err)
+	if addr := cfg.Telemetry.AdminAddr(); addr != "" {
+		t.Logf("telemetry metrics: http://%s/metrics", addr)
+	}
 
 	// start server in goroutine for non-blocking
 	go func() {
@@ -172,15 +155,25 @@ func setupTest(t *testing.T, fn func(*Config)) (
 		rootConn.Close()
 		nobodyConn.Close()
 		l.Close()
-		if telemetryExporter != nil {
-			time.Sleep(1500 * time.Millisecond)
-			telemetryExporter.Stop()
-			telemetryExporter.Close()
-		}
+		require.NoError(t, cfg.Telemetry.Shutdown(context.Background()))
 	}
 
 }
 
+// telemetryConfigForTest wires telemetry on only when -debug is set, so a
+// normal test run doesn't pay for spinning up an otel SDK it won't look at.
+func telemetryConfigForTest(t *testing.T) *telemetry.Config {
+	t.Helper()
+	if !*debug {
+		return nil
+	}
+	return &telemetry.Config{
+		ServiceName: "proglog-test",
+		Exporter:    telemetry.ExporterPrometheus,
+		AdminAddr:   "127.0.0.1:0",
+	}
+}
+
 func testProduceConsume(t *testing.T, client, _ api.LogClient, config *Config) {
 	// creates context for cancellation
 	ctx := context.Background()
@@ -293,3 +286,11 @@ func testUnathorized(t *testing.T, _, client api.LogClient, config *Config) {
 		t.Fatalf("got code: %d, want: %d", gotCode, wantCode)
 	}
 }
+
+// testJoinUnimplemented exercises the setupTest CommitLog, a plain
+// internal/log.Log, which doesn't support cluster membership changes. Join
+// should report Unimplemented rather than panicking.
+func testJoinUnimplemented(t *testing.T, client, _ api.LogClient, _ *Config) {
+	_, err := client.Join(context.Background(), &api.JoinRequest{Id: "1", Addr: "127.0.0.1:0"})
+	require.Equal(t, codes.Unimplemented, status.Code(err))
+}
@@ -0,0 +1,290 @@
+// Package telemetry wires a proglog node into OpenTelemetry: a tracer
+// provider that records a span per gRPC call via otelgrpc, a meter provider
+// recording per-RPC latency histograms and produce/consume byte counters,
+// and zap logs correlated to the active span through Logger. Which backend
+// the data is published to - a local Prometheus /metrics endpoint or an
+// OTLP-gRPC collector - is selected by Config.Exporter.
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	otelprometheus "go.opentelemetry.io/otel/exporters/prometheus"
+	"go.opentelemetry.io/otel/metric"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/stats"
+)
+
+// Exporter selects where spans and metrics are published.
+type Exporter string
+
+const (
+	// ExporterPrometheus serves metrics for scraping on Config.AdminAddr.
+	// Prometheus has no model for traces, so in this mode spans are still
+	// created (log correlation via Logger keeps working) but never leave
+	// the process.
+	ExporterPrometheus Exporter = "prometheus"
+	// ExporterOTLP ships both traces and metrics to Config.OTLPEndpoint
+	// over OTLP-gRPC.
+	ExporterOTLP Exporter = "otlp"
+)
+
+// Config selects and configures the exporter a node's Telemetry publishes
+// through. A nil *Config disables tracing and metrics entirely.
+type Config struct {
+	// ServiceName is attached to every span and metric as the otel
+	// service.name resource attribute.
+	ServiceName string
+	Exporter    Exporter
+
+	// AdminAddr is the address the Prometheus /metrics endpoint is served
+	// on. Only used when Exporter == ExporterPrometheus.
+	AdminAddr string
+
+	// OTLPEndpoint is the collector address dialed for traces and metrics.
+	// Only used when Exporter == ExporterOTLP.
+	OTLPEndpoint string
+	// OTLPInsecure disables transport security on the OTLP-gRPC connection,
+	// for collectors running without TLS (e.g. a local sidecar).
+	OTLPInsecure bool
+}
+
+// Telemetry bundles the tracer/meter providers a node publishes through,
+// the gRPC stats handler that records a span and a latency sample per RPC,
+// and the admin HTTP server backing the Prometheus exporter, if any. The
+// nil *Telemetry is a safe no-op, so callers can wire Config.Telemetry
+// unconditionally and defer Shutdown regardless of whether telemetry is
+// enabled.
+type Telemetry struct {
+	tracerProvider *sdktrace.TracerProvider
+	meterProvider  *sdkmetric.MeterProvider
+	statsHandler   stats.Handler
+
+	rpcLatency   metric.Float64Histogram
+	produceBytes metric.Int64Counter
+	consumeBytes metric.Int64Counter
+
+	adminServer *http.Server
+	adminAddr   string
+	logger      *zap.Logger
+}
+
+// New builds a Telemetry from cfg, wiring up the tracer/meter providers and
+// their exporter. New(nil) returns a nil *Telemetry, which every method on
+// this type treats as a no-op.
+func New(cfg *Config) (*Telemetry, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+
+	t := &Telemetry{logger: zap.L().Named("telemetry")}
+	res, err := resource.New(context.Background(),
+		resource.WithAttributes(semconv.ServiceName(cfg.ServiceName)),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("building otel resource: %w", err)
+	}
+
+	switch cfg.Exporter {
+	case ExporterOTLP:
+		if err := t.setupOTLP(context.Background(), cfg, res); err != nil {
+			return nil, err
+		}
+	case ExporterPrometheus, "":
+		if err := t.setupPrometheus(cfg, res); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("telemetry: unknown exporter %q", cfg.Exporter)
+	}
+
+	t.statsHandler = otelgrpc.NewServerHandler(
+		otelgrpc.WithTracerProvider(t.tracerProvider),
+		otelgrpc.WithMeterProvider(t.meterProvider),
+	)
+
+	meter := t.meterProvider.Meter("github.com/siluk00/proglog/internal/server")
+	if t.rpcLatency, err = meter.Float64Histogram(
+		"proglog.server.rpc.latency",
+		metric.WithDescription("Latency of a Produce/Consume RPC"),
+		metric.WithUnit("s"),
+	); err != nil {
+		return nil, fmt.Errorf("registering rpc latency histogram: %w", err)
+	}
+	if t.produceBytes, err = meter.Int64Counter(
+		"proglog.server.produce.bytes",
+		metric.WithDescription("Bytes appended to the log via Produce"),
+	); err != nil {
+		return nil, fmt.Errorf("registering produce bytes counter: %w", err)
+	}
+	if t.consumeBytes, err = meter.Int64Counter(
+		"proglog.server.consume.bytes",
+		metric.WithDescription("Bytes read from the log via Consume"),
+	); err != nil {
+		return nil, fmt.Errorf("registering consume bytes counter: %w", err)
+	}
+
+	return t, nil
+}
+
+func (t *Telemetry) setupOTLP(ctx context.Context, cfg *Config, res *resource.Resource) error {
+	traceOpts := []otlptracegrpc.Option{otlptracegrpc.WithEndpoint(cfg.OTLPEndpoint)}
+	metricOpts := []otlpmetricgrpc.Option{otlpmetricgrpc.WithEndpoint(cfg.OTLPEndpoint)}
+	if cfg.OTLPInsecure {
+		traceOpts = append(traceOpts, otlptracegrpc.WithInsecure())
+		metricOpts = append(metricOpts, otlpmetricgrpc.WithInsecure())
+	}
+
+	traceExporter, err := otlptracegrpc.New(ctx, traceOpts...)
+	if err != nil {
+		return fmt.Errorf("dialing otlp trace exporter: %w", err)
+	}
+	t.tracerProvider = sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(traceExporter),
+		sdktrace.WithResource(res),
+	)
+
+	metricExporter, err := otlpmetricgrpc.New(ctx, metricOpts...)
+	if err != nil {
+		return fmt.Errorf("dialing otlp metric exporter: %w", err)
+	}
+	t.meterProvider = sdkmetric.NewMeterProvider(
+		sdkmetric.WithReader(sdkmetric.NewPeriodicReader(metricExporter)),
+		sdkmetric.WithResource(res),
+	)
+	return nil
+}
+
+func (t *Telemetry) setupPrometheus(cfg *Config, res *resource.Resource) error {
+	t.tracerProvider = sdktrace.NewTracerProvider(sdktrace.WithResource(res))
+
+	exporter, err := otelprometheus.New()
+	if err != nil {
+		return fmt.Errorf("building prometheus exporter: %w", err)
+	}
+	t.meterProvider = sdkmetric.NewMeterProvider(
+		sdkmetric.WithReader(exporter),
+		sdkmetric.WithResource(res),
+	)
+
+	ln, err := net.Listen("tcp", cfg.AdminAddr)
+	if err != nil {
+		return fmt.Errorf("binding admin address %s: %w", cfg.AdminAddr, err)
+	}
+	t.adminAddr = ln.Addr().String()
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	t.adminServer = &http.Server{Handler: mux}
+	go func() {
+		if err := t.adminServer.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			t.logger.Error("admin metrics server stopped", zap.Error(err))
+		}
+	}()
+	return nil
+}
+
+// StatsHandler returns the gRPC stats handler that records a span for every
+// unary and stream call. Safe to call on a nil *Telemetry: it returns nil,
+// which grpc.StatsHandler treats as "don't install one".
+func (t *Telemetry) StatsHandler() stats.Handler {
+	if t == nil {
+		return nil
+	}
+	return t.statsHandler
+}
+
+// AdminAddr returns the actual address the Prometheus /metrics endpoint is
+// listening on, including the OS-assigned port if AdminAddr was ":0"-style.
+// Empty when metrics aren't served (the OTLP exporter, or a nil Telemetry).
+func (t *Telemetry) AdminAddr() string {
+	if t == nil {
+		return ""
+	}
+	return t.adminAddr
+}
+
+// ObserveRPC records how long the RPC named name took, measured from start.
+// Safe to call on a nil *Telemetry.
+func (t *Telemetry) ObserveRPC(ctx context.Context, name string, start time.Time) {
+	if t == nil {
+		return
+	}
+	t.rpcLatency.Record(ctx, time.Since(start).Seconds(),
+		metric.WithAttributes(attribute.String("rpc", name)))
+}
+
+// AddProduceBytes records n bytes appended by a Produce call. Safe to call
+// on a nil *Telemetry.
+func (t *Telemetry) AddProduceBytes(ctx context.Context, n int) {
+	if t == nil {
+		return
+	}
+	t.produceBytes.Add(ctx, int64(n))
+}
+
+// AddConsumeBytes records n bytes returned by a Consume call. Safe to call
+// on a nil *Telemetry.
+func (t *Telemetry) AddConsumeBytes(ctx context.Context, n int) {
+	if t == nil {
+		return
+	}
+	t.consumeBytes.Add(ctx, int64(n))
+}
+
+// Logger returns a zap logger named named, carrying trace_id/span_id fields
+// for the span active in ctx so a node's structured logs can be correlated
+// back to the trace and metrics covering the same RPC. Safe to call on a
+// nil *Telemetry, and falls back to an uncorrelated logger when ctx carries
+// no valid span.
+func (t *Telemetry) Logger(ctx context.Context, named string) *zap.Logger {
+	base := zap.L().Named(named)
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return base
+	}
+	return base.With(
+		zap.String("trace_id", sc.TraceID().String()),
+		zap.String("span_id", sc.SpanID().String()),
+	)
+}
+
+// Shutdown flushes and tears down the tracer/meter providers and stops the
+// admin HTTP server, if one is running. Safe to call on a nil *Telemetry.
+func (t *Telemetry) Shutdown(ctx context.Context) error {
+	if t == nil {
+		return nil
+	}
+	var errs []error
+	if t.adminServer != nil {
+		if err := t.adminServer.Shutdown(ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if t.tracerProvider != nil {
+		if err := t.tracerProvider.Shutdown(ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if t.meterProvider != nil {
+		if err := t.meterProvider.Shutdown(ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}